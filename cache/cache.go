@@ -0,0 +1,121 @@
+// Package cache provides a persistent cache of image orientations and
+// dimensions, so that repeated scans of the same files can skip reading
+// the image metadata.
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adalberht/imageorient"
+)
+
+// Key identifies a version of a file.
+type Key struct {
+	// Path is the absolute path of the file.
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Entry holds the cached metadata of an image.
+type Entry struct {
+	// Orientation is the EXIF orientation tag value (0 if not found or invalid).
+	Orientation int `json:"orientation"`
+	// Width and Height are the image dimensions with the respect to
+	// the EXIF orientation tag (if present).
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Err is the error reading the file as an image, e.g. for unsupported
+	// formats. It is cached so that such files are not read again until
+	// they change.
+	Err string `json:"err,omitempty"`
+}
+
+// ImageError is returned by Cache.Lookup for files that could not
+// be read as images, including the failures found in the store.
+type ImageError struct {
+	Path string
+	Err  string
+}
+
+// Error implements the error interface.
+func (e *ImageError) Error() string {
+	return e.Path + ": " + e.Err
+}
+
+// Store persists cache entries.
+type Store interface {
+	// Get returns the entry stored for the given key
+	// and whether it was found.
+	Get(key Key) (Entry, bool, error)
+	// Put stores the entry for the given key.
+	Put(key Key, entry Entry) error
+}
+
+// Cache reads image metadata through a Store.
+type Cache struct {
+	store Store
+}
+
+// New returns a Cache backed by the given store.
+func New(store Store) *Cache {
+	return &Cache{
+		store: store,
+	}
+}
+
+// Lookup returns the metadata of the image at the given path. The image is
+// only read if the store has no entry for its current size and modification time.
+// Files that cannot be read as images are reported with an *ImageError.
+func (c *Cache) Lookup(path string) (Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	key := Key{Path: path, Size: fi.Size(), ModTime: fi.ModTime()}
+
+	entry, ok, err := c.store.Get(key)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !ok {
+		entry, err = probe(path)
+		if err != nil {
+			return Entry{}, err
+		}
+		if err := c.store.Put(key, entry); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	if entry.Err != "" {
+		return Entry{}, &ImageError{Path: path, Err: entry.Err}
+	}
+	return entry, nil
+}
+
+// probe reads the metadata of the image at the given path. Errors reading
+// the file as an image are returned in the entry, so that they are cached.
+func probe(path string) (Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	p, err := imageorient.Probe(f)
+	if err != nil {
+		return Entry{Err: err.Error()}, nil
+	}
+	return Entry{
+		Orientation: p.Orientation,
+		Width:       p.Width,
+		Height:      p.Height,
+	}, nil
+}
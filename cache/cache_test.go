@@ -0,0 +1,221 @@
+package cache
+
+import (
+	_ "image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingStore struct {
+	Store
+	puts int
+}
+
+func (s *countingStore) Put(key Key, entry Entry) error {
+	s.puts++
+	return s.Store.Put(key, entry)
+}
+
+// tempDir creates a temporary directory and returns
+// a function removing it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "imageorient")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestLookup(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	b, err := ioutil.ReadFile("../testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	img := filepath.Join(dir, "image.jpg")
+	if err := ioutil.WriteFile(img, b, 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	storePath := filepath.Join(dir, "cache.json")
+	fs, err := OpenFileStore(storePath)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	store := &countingStore{Store: fs}
+	c := New(store)
+
+	entry, err := c.Lookup(img)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if entry.Orientation != 6 {
+		t.Errorf("Wanted orientation=6, got %d", entry.Orientation)
+	}
+	if _, err := c.Lookup(img); err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if store.puts != 1 {
+		t.Errorf("Wanted 1 store update, got %d", store.puts)
+	}
+	if err := fs.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A reopened store should have the entry persisted.
+	fs, err = OpenFileStore(storePath)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	store = &countingStore{Store: fs}
+	c = New(store)
+	cached, err := c.Lookup(img)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if cached != entry {
+		t.Errorf("Wanted %+v, got %+v", entry, cached)
+	}
+	if store.puts != 0 {
+		t.Errorf("Wanted no store updates, got %d", store.puts)
+	}
+
+	// Modified files should be read again.
+	mtime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(img, mtime, mtime); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := c.Lookup(img); err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if store.puts != 1 {
+		t.Errorf("Wanted 1 store update, got %d", store.puts)
+	}
+}
+
+func TestLookupShouldNormalizePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	abs := filepath.Join(wd, "..", "testdata", "orientation_6.jpg")
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	fs, err := OpenFileStore(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	store := &countingStore{Store: fs}
+	c := New(store)
+
+	for _, path := range []string{"../testdata/orientation_6.jpg", abs, "./../testdata/orientation_6.jpg"} {
+		if _, err := c.Lookup(path); err != nil {
+			t.Fatalf("Lookup(%q): %v", path, err)
+		}
+	}
+	if store.puts != 1 {
+		t.Errorf("Wanted 1 store update, got %d", store.puts)
+	}
+}
+
+func TestFileStorePrune(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	kept := filepath.Join(dir, "kept.jpg")
+	if err := ioutil.WriteFile(kept, nil, 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+	removed := filepath.Join(dir, "removed.jpg")
+
+	fs, err := OpenFileStore(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	for _, path := range []string{kept, removed} {
+		if err := fs.Put(Key{Path: path}, Entry{}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if err := fs.Prune(); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	if _, ok, _ := fs.Get(Key{Path: kept}); !ok {
+		t.Errorf("Wanted entry of %s to be kept", kept)
+	}
+	if _, ok, _ := fs.Get(Key{Path: removed}); ok {
+		t.Errorf("Wanted entry of %s to be removed", removed)
+	}
+
+	fs.Delete(kept)
+	if _, ok, _ := fs.Get(Key{Path: kept}); ok {
+		t.Errorf("Wanted entry of %s to be deleted", kept)
+	}
+}
+
+func TestFileStoreSaveShouldKeepMode(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "cache.json")
+	if err := ioutil.WriteFile(path, []byte("{}"), 0640); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	fs, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	if err := fs.Put(Key{Path: "/image.jpg"}, Entry{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := fs.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("Wanted mode %v, got %v", os.FileMode(0640), fi.Mode().Perm())
+	}
+}
+
+func TestLookupShouldCacheUnsupportedFiles(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "video.mov")
+	if err := ioutil.WriteFile(path, []byte("not an image"), 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	fs, err := OpenFileStore(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	store := &countingStore{Store: fs}
+	c := New(store)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Lookup(path)
+		if _, ok := err.(*ImageError); !ok {
+			t.Errorf("Wanted *ImageError, got: %v", err)
+		}
+	}
+	if store.puts != 1 {
+		t.Errorf("Wanted 1 store update, got %d", store.puts)
+	}
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileRecord is a single entry of the file store.
+type fileRecord struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Entry
+}
+
+// FileStore is a Store that keeps the entries in memory and saves them
+// to a JSON file. Only the latest version of every path is kept, but entries
+// of deleted or renamed files are kept until they are removed with Delete
+// or Prune.
+type FileStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]fileRecord
+	dirty   bool
+}
+
+// OpenFileStore loads the entries from the file at the given path.
+// The file does not need to exist yet.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		records: make(map[string]fileRecord),
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.records); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get implements the Store interface.
+func (s *FileStore) Get(key Key) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key.Path]
+	if !ok || r.Size != key.Size || !r.ModTime.Equal(key.ModTime) {
+		return Entry{}, false, nil
+	}
+	return r.Entry, true, nil
+}
+
+// Put implements the Store interface. The entry is not persisted
+// until Save is called.
+func (s *FileStore) Put(key Key, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key.Path] = fileRecord{
+		Size:    key.Size,
+		ModTime: key.ModTime,
+		Entry:   entry,
+	}
+	s.dirty = true
+	return nil
+}
+
+// Delete removes the entry of the file at the given absolute path.
+func (s *FileStore) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[path]; ok {
+		delete(s.records, path)
+		s.dirty = true
+	}
+}
+
+// Prune removes the entries of the files that no longer exist. The files
+// are checked without blocking the other methods. Errors checking a file
+// do not stop the pruning, the first one is returned.
+func (s *FileStore) Prune() error {
+	s.mu.Lock()
+	paths := make([]string, 0, len(s.records))
+	for path := range s.records {
+		paths = append(paths, path)
+	}
+	s.mu.Unlock()
+
+	var (
+		removed  []string
+		firstErr error
+	)
+	for _, path := range paths {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			removed = append(removed, path)
+			continue
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range removed {
+		if _, ok := s.records[path]; ok {
+			delete(s.records, path)
+			s.dirty = true
+		}
+	}
+	return firstErr
+}
+
+// Save writes the entries to the file if they changed since
+// the store was opened or last saved. The permissions of an existing
+// file are kept, new files are created with mode 0644.
+func (s *FileStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	b, err := json.Marshal(s.records)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that an interrupted
+	// save does not corrupt the existing file.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(s.path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	"math"
+
+	"github.com/adalberht/imageorient"
+)
+
+// headerLen returns the number of bytes to copy from a C buffer of the given
+// size to read the EXIF orientation tag.
+func headerLen(size uint64) int {
+	if size > imageorient.MaxBufLen {
+		return imageorient.MaxBufLen
+	}
+	return int(size)
+}
+
+// bufLen returns the number of bytes to copy from a C buffer of the given size
+// and whether the buffer can be copied at all. Buffers larger than
+// math.MaxInt32 bytes cannot be passed to C.GoBytes.
+func bufLen(size uint64) (int, bool) {
+	if size > math.MaxInt32 {
+		return 0, false
+	}
+	return int(size), true
+}
@@ -0,0 +1,51 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adalberht/imageorient"
+)
+
+func TestHeaderLen(t *testing.T) {
+	testCases := []struct {
+		size uint64
+		want int
+	}{
+		{0, 0},
+		{100, 100},
+		{imageorient.MaxBufLen, imageorient.MaxBufLen},
+		{imageorient.MaxBufLen + 1, imageorient.MaxBufLen},
+		{math.MaxUint32 + 1, imageorient.MaxBufLen},
+	}
+
+	for _, tc := range testCases {
+		if got := headerLen(tc.size); got != tc.want {
+			t.Errorf("headerLen(%d): wanted %d, got %d", tc.size, tc.want, got)
+		}
+	}
+}
+
+func TestBufLen(t *testing.T) {
+	testCases := []struct {
+		size uint64
+		want int
+		ok   bool
+	}{
+		{0, 0, true},
+		{100, 100, true},
+		{math.MaxInt32, math.MaxInt32, true},
+		{math.MaxInt32 + 1, 0, false},
+		{math.MaxUint32 + 1, 0, false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := bufLen(tc.size)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("bufLen(%d): wanted (%d, %v), got (%d, %v)", tc.size, tc.want, tc.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+/*
+#include <stdlib.h>
+
+extern int imageorient_get_orientation(unsigned char *data, size_t size);
+extern int imageorient_fix_orientation(unsigned char *data, size_t size, unsigned char **out, size_t *out_size);
+extern void imageorient_free(void *p);
+*/
+import "C"
+
+import "unsafe"
+
+// The functions below call the exported functions through the C ABI.
+// They are used by the tests, which cannot use cgo.
+
+// cBytes returns a C copy of b, or NULL if b is nil.
+func cBytes(b []byte) *C.uchar {
+	if b == nil {
+		return nil
+	}
+	return (*C.uchar)(C.CBytes(b))
+}
+
+// callGetOrientation calls imageorient_get_orientation with a C copy
+// of data (NULL if data is nil).
+func callGetOrientation(data []byte) int {
+	p := cBytes(data)
+	defer C.free(unsafe.Pointer(p))
+	return int(C.imageorient_get_orientation(p, C.size_t(len(data))))
+}
+
+// callFixOrientation calls imageorient_fix_orientation with a C copy of data
+// (NULL if data is nil) and returns its result and a copy of the output.
+// If nullOut is set, NULL is passed as out and out_size.
+func callFixOrientation(data []byte, nullOut bool) (int, []byte) {
+	p := cBytes(data)
+	defer C.free(unsafe.Pointer(p))
+
+	if nullOut {
+		return int(C.imageorient_fix_orientation(p, C.size_t(len(data)), nil, nil)), nil
+	}
+	var (
+		out     *C.uchar
+		outSize C.size_t
+	)
+	ret := int(C.imageorient_fix_orientation(p, C.size_t(len(data)), &out, &outSize))
+	if out == nil {
+		return ret, nil
+	}
+	defer C.imageorient_free(unsafe.Pointer(out))
+	return ret, C.GoBytes(unsafe.Pointer(out), C.int(outSize))
+}
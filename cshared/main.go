@@ -0,0 +1,87 @@
+// Command cshared exports the imageorient orientation handling
+// through the C ABI for non-Go consumers.
+//
+// Build the shared library and its header with:
+//
+//	go build -buildmode=c-shared -o libimageorient.so github.com/adalberht/imageorient/cshared
+//
+// The exported functions are:
+//
+//	int imageorient_get_orientation(unsigned char *data, size_t size);
+//	int imageorient_fix_orientation(unsigned char *data, size_t size, unsigned char **out, size_t *out_size);
+//	void imageorient_free(void *p);
+//
+// imageorient_get_orientation returns the EXIF orientation tag value
+// (0 if not found or invalid), or -1 on error. Only the first 1 MiB
+// (imageorient.MaxBufLen) of the buffer is read.
+//
+// imageorient_fix_orientation decodes the image, fixes its orientation and
+// encodes it back (JPEG images as JPEG, all other formats as PNG). It returns
+// 0 if the orientation was fixed, 1 if the image did not need to be fixed and
+// is returned unchanged, and -1 on error. The buffer must be smaller than
+// 2 GiB, and out and out_size must not be NULL. Unless an error is returned,
+// *out points to a buffer of *out_size bytes that must be released with
+// imageorient_free.
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/adalberht/imageorient/internal/orient"
+)
+
+func main() {}
+
+//export imageorient_get_orientation
+func imageorient_get_orientation(data *C.uchar, size C.size_t) (ret C.int) {
+	defer func() {
+		if recover() != nil {
+			ret = -1
+		}
+	}()
+
+	return C.int(orient.GetOrientation(goBytes(data, headerLen(uint64(size)))))
+}
+
+//export imageorient_fix_orientation
+func imageorient_fix_orientation(data *C.uchar, size C.size_t, out **C.uchar, outSize *C.size_t) (ret C.int) {
+	defer func() {
+		if recover() != nil {
+			ret = -1
+		}
+	}()
+
+	if out == nil || outSize == nil {
+		return -1
+	}
+	n, ok := bufLen(uint64(size))
+	if !ok {
+		return -1
+	}
+	b, _, fixed, err := orient.DecodeAndFix(goBytes(data, n))
+	if err != nil {
+		return -1
+	}
+	*out = (*C.uchar)(C.CBytes(b))
+	*outSize = C.size_t(len(b))
+	if !fixed {
+		return 1
+	}
+	return 0
+}
+
+//export imageorient_free
+func imageorient_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// goBytes copies the first n bytes of the given C buffer.
+func goBytes(data *C.uchar, n int) []byte {
+	if data == nil || n == 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(data), C.int(n))
+}
@@ -0,0 +1,111 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetOrientation(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"NULL data", nil, 0},
+		{"valid image", b, 6},
+		{"garbage", []byte("not an image"), 0},
+	}
+
+	for _, tc := range testCases {
+		if got := callGetOrientation(tc.data); got != tc.want {
+			t.Errorf("%s: wanted %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestFixOrientation(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	upright, err := ioutil.ReadFile("../testdata/orientation_1.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if ret, out := callFixOrientation(nil, false); ret != -1 || out != nil {
+		t.Errorf("NULL data: wanted -1 without output, got %d with %d bytes", ret, len(out))
+	}
+	if ret, _ := callFixOrientation(b, true); ret != -1 {
+		t.Errorf("NULL out: wanted -1, got %d", ret)
+	}
+	if ret, out := callFixOrientation([]byte("not an image"), false); ret != -1 || out != nil {
+		t.Errorf("garbage: wanted -1 without output, got %d with %d bytes", ret, len(out))
+	}
+
+	ret, out := callFixOrientation(upright, false)
+	if ret != 1 || !bytes.Equal(out, upright) {
+		t.Errorf("upright image: wanted 1 with unchanged data, got %d", ret)
+	}
+
+	ret, out = callFixOrientation(b, false)
+	if ret != 0 {
+		t.Fatalf("valid image: wanted 0, got %d", ret)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	fixed, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("valid image: %v", err)
+	}
+	if format != "jpeg" || fixed.Width != cfg.Height || fixed.Height != cfg.Width {
+		t.Errorf("valid image: wanted %dx%d jpeg, got %dx%d %s", cfg.Height, cfg.Width, fixed.Width, fixed.Height, format)
+	}
+}
+
+func TestBuildCShared(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping c-shared build in short mode")
+	}
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("skipping c-shared build without a C compiler")
+	}
+
+	dir, err := ioutil.TempDir("", "imageorient")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, "libimageorient.so")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", lib, ".")
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build -buildmode=c-shared: %v\n%s", err, out)
+	}
+
+	header, err := ioutil.ReadFile(filepath.Join(dir, "libimageorient.h"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, name := range []string{"imageorient_get_orientation", "imageorient_fix_orientation", "imageorient_free"} {
+		if !bytes.Contains(header, []byte(name)) {
+			t.Errorf("%s not exported", name)
+		}
+	}
+}
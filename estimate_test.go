@@ -0,0 +1,115 @@
+package imageorient_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/adalberht/imageorient"
+	"github.com/adalberht/imageorient/internal/orient"
+)
+
+func TestEstimateMemoryIsUpperBound(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// A larger image, so that the size of the decoded image dominates the
+	// estimate. The EXIF metadata (the APP1 block following the SOI marker)
+	// of the test file is copied to it to have its orientation fixed too.
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewYCbCr(image.Rect(0, 0, 1000, 750), image.YCbCrSubsampleRatio420), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	app1 := b[2 : 4+(int(b[4])<<8|int(b[5]))]
+	large := append(append([]byte{0xff, 0xd8}, app1...), buf.Bytes()[2:]...)
+
+	// PNG images whose decoded size is not reflected in the config.
+	trns := encodeGrayPNG(t, 3000, true, false)
+	interlaced := encodeGrayPNG(t, 3000, false, true)
+
+	for _, data := range [][]byte{b, buf.Bytes(), large, trns, interlaced} {
+		orientation, r := imageorient.GetOrientation(bytes.NewReader(data))
+		cfg, format, err := image.DecodeConfig(r)
+		if err != nil {
+			t.Fatalf("image.DecodeConfig: %v", err)
+		}
+		estimate := imageorient.EstimateMemory(cfg, format, orientation)
+
+		d := imageorient.NewDecoder(orient.FixOrientationFunctions())
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		if _, _, err := d.Decode(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		runtime.ReadMemStats(&after)
+
+		allocated := int64(after.TotalAlloc - before.TotalAlloc)
+		if estimate < allocated {
+			t.Errorf("%s %dx%d, orientation %d: estimate %d is lower than the allocated %d bytes", format, cfg.Width, cfg.Height, orientation, estimate, allocated)
+		}
+		t.Logf("%s %dx%d, orientation %d: estimate %d, allocated %d bytes", format, cfg.Width, cfg.Height, orientation, estimate, allocated)
+	}
+}
+
+// encodeGrayPNG returns an 8-bit grayscale PNG image of the given size,
+// optionally with a tRNS chunk or Adam7 interlacing, which image/png
+// does not encode.
+func encodeGrayPNG(t *testing.T, size int, trns, interlaced bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	writeChunk := func(typ string, data []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(typ))
+		crc.Write(data)
+		buf.WriteString(typ)
+		buf.Write(data)
+		binary.Write(&buf, binary.BigEndian, crc.Sum32())
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(size))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(size))
+	ihdr[8] = 8 // Bit depth.
+	if interlaced {
+		ihdr[12] = 1
+	}
+	writeChunk("IHDR", ihdr)
+	if trns {
+		writeChunk("tRNS", []byte{0, 0})
+	}
+
+	// Every row is a filter type byte followed by the pixels, all zeros.
+	rows := size * (size + 1)
+	if interlaced {
+		rows = 0
+		for _, p := range [][4]int{{0, 0, 8, 8}, {4, 0, 8, 8}, {0, 4, 4, 8}, {2, 0, 4, 4}, {0, 2, 2, 4}, {1, 0, 2, 2}, {0, 1, 1, 2}} {
+			w := (size - p[0] + p[2] - 1) / p[2]
+			h := (size - p[1] + p[3] - 1) / p[3]
+			if w > 0 && h > 0 {
+				rows += h * (w + 1)
+			}
+		}
+	}
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	if _, err := zw.Write(make([]byte, rows)); err != nil {
+		t.Fatalf("zlib: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zlib: %v", err)
+	}
+	writeChunk("IDAT", idat.Bytes())
+	writeChunk("IEND", nil)
+	return buf.Bytes()
+}
@@ -12,27 +12,52 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"io"
 	"io/ioutil"
 )
 
-// maxBufLen is the maximum size of a buffer that should be enough to read
+// MaxBufLen is the maximum size of a buffer that should be enough to read
 // the EXIF metadata. According to the EXIF specs, it is located inside the
-// APP1 block that goes right after the start of image (SOI).
-const maxBufLen = 1 << 20
+// APP1 block that goes right after the start of image (SOI). Only the first
+// MaxBufLen bytes of an image are read to find the EXIF orientation tag.
+const MaxBufLen = 1 << 20
 
 // getOrientation returns the EXIF orientation tag from the given image
 // and a new io.Reader with the same state as the original reader r.
 func getOrientation(r io.Reader) (int, io.Reader) {
+	orientation, _, r := getEXIF(r)
+	return orientation, r
+}
+
+// getEXIF returns the EXIF orientation tag from the given image, whether
+// the EXIF metadata is present and a new io.Reader with the same state
+// as the original reader r.
+func getEXIF(r io.Reader) (int, bool, io.Reader) {
 	buf := new(bytes.Buffer)
-	tr := io.TeeReader(io.LimitReader(r, maxBufLen), buf)
-	orientation := readOrientation(tr)
-	return orientation, io.MultiReader(buf, r)
+	tr := io.TeeReader(io.LimitReader(r, MaxBufLen), buf)
+	orientation, hasEXIF := readEXIF(tr)
+	return orientation, hasEXIF, io.MultiReader(buf, r)
+}
+
+// GetOrientation returns the EXIF orientation tag from the given image
+// (0 if not found or invalid) and a new io.Reader with the same state
+// as the original reader r.
+func GetOrientation(r io.Reader) (int, io.Reader) {
+	return getOrientation(r)
 }
 
 // readOrientation reads the EXIF orientation tag from the given image.
 // It returns 0 if the orientation tag is not found or invalid.
 func readOrientation(r io.Reader) int {
+	orientation, _ := readEXIF(r)
+	return orientation
+}
+
+// readEXIF reads the EXIF orientation tag from the given image and reports
+// whether the EXIF metadata is present. The orientation is 0 if the tag
+// is not found or invalid.
+func readEXIF(r io.Reader) (int, bool) {
 	const (
 		markerSOI      = 0xffd8
 		markerAPP1     = 0xffe1
@@ -45,45 +70,45 @@ func readOrientation(r io.Reader) int {
 	// Check if JPEG SOI marker is present.
 	var soi uint16
 	if err := binary.Read(r, binary.BigEndian, &soi); err != nil {
-		return 0
+		return 0, false
 	}
 	if soi != markerSOI {
-		return 0 // Missing JPEG SOI marker.
+		return 0, false // Missing JPEG SOI marker.
 	}
 
 	// Find JPEG APP1 marker.
 	for {
 		var marker, size uint16
 		if err := binary.Read(r, binary.BigEndian, &marker); err != nil {
-			return 0
+			return 0, false
 		}
 		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
-			return 0
+			return 0, false
 		}
 		if marker>>8 != 0xff {
-			return 0 // Invalid JPEG marker.
+			return 0, false // Invalid JPEG marker.
 		}
 		if marker == markerAPP1 {
 			break
 		}
 		if size < 2 {
-			return 0 // Invalid block size.
+			return 0, false // Invalid block size.
 		}
 		if _, err := io.CopyN(ioutil.Discard, r, int64(size-2)); err != nil {
-			return 0
+			return 0, false
 		}
 	}
 
 	// Check if EXIF header is present.
 	var header uint32
 	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
-		return 0
+		return 0, false
 	}
 	if header != exifHeader {
-		return 0
+		return 0, false
 	}
 	if _, err := io.CopyN(ioutil.Discard, r, 2); err != nil {
-		return 0
+		return 0, true
 	}
 
 	// Read byte order information.
@@ -92,7 +117,7 @@ func readOrientation(r io.Reader) int {
 		byteOrder    binary.ByteOrder
 	)
 	if err := binary.Read(r, binary.BigEndian, &byteOrderTag); err != nil {
-		return 0
+		return 0, true
 	}
 	switch byteOrderTag {
 	case byteOrderBE:
@@ -100,55 +125,55 @@ func readOrientation(r io.Reader) int {
 	case byteOrderLE:
 		byteOrder = binary.LittleEndian
 	default:
-		return 0 // Invalid byte order flag.
+		return 0, true // Invalid byte order flag.
 	}
 	if _, err := io.CopyN(ioutil.Discard, r, 2); err != nil {
-		return 0
+		return 0, true
 	}
 
 	// Skip the EXIF offset.
 	var offset uint32
 	if err := binary.Read(r, byteOrder, &offset); err != nil {
-		return 0
+		return 0, true
 	}
 	if offset < 8 {
-		return 0 // Invalid offset value.
+		return 0, true // Invalid offset value.
 	}
 	if _, err := io.CopyN(ioutil.Discard, r, int64(offset-8)); err != nil {
-		return 0
+		return 0, true
 	}
 
 	// Read the number of tags.
 	var numTags uint16
 	if err := binary.Read(r, byteOrder, &numTags); err != nil {
-		return 0
+		return 0, true
 	}
 
 	// Find the orientation tag.
 	for i := 0; i < int(numTags); i++ {
 		var tag uint16
 		if err := binary.Read(r, byteOrder, &tag); err != nil {
-			return 0
+			return 0, true
 		}
 		if tag != orientationTag {
 			if _, err := io.CopyN(ioutil.Discard, r, 10); err != nil {
-				return 0
+				return 0, true
 			}
 			continue
 		}
 		if _, err := io.CopyN(ioutil.Discard, r, 6); err != nil {
-			return 0
+			return 0, true
 		}
 		var val uint16
 		if err := binary.Read(r, byteOrder, &val); err != nil {
-			return 0
+			return 0, true
 		}
 		if val < 1 || val > 8 {
-			return 0 // Invalid tag value.
+			return 0, true // Invalid tag value.
 		}
-		return int(val)
+		return int(val), true
 	}
-	return 0 // Missing orientation tag.
+	return 0, true // Missing orientation tag.
 }
 
 type Decoder interface {
@@ -159,11 +184,20 @@ type Decoder interface {
 // Function needed to fix the given image orientation
 type FixOrientationFunction func(img image.Image) (image.Image, error)
 
+// Transform is a single stage of the decoding pipeline. It receives the image
+// returned by the previous stage and its orientation, and returns the transformed
+// image and its orientation. The first stage receives the EXIF orientation tag
+// value of the decoded image (0 if not found or invalid). Stages that fix the
+// orientation, such as FixOrientation, return 1, while other stages usually
+// return the orientation they received.
+type Transform func(img image.Image, orientation int) (image.Image, int, error)
+
 type decoder struct {
-	FixOrientationFunctions map[int]FixOrientationFunction
+	transforms []Transform
 }
 
-// Decode decodes an image and changes its orientation
+// Decode decodes an image and passes it through the decoder transforms
+// in order, which usually includes fixing the image orientation
 // according to the EXIF orientation tag (if present).
 func (d *decoder) Decode(r io.Reader) (image.Image, string, error) {
 	orientation, r := getOrientation(r)
@@ -172,10 +206,13 @@ func (d *decoder) Decode(r io.Reader) (image.Image, string, error) {
 	if err != nil {
 		return img, format, err
 	}
-	if orientation > 1 {
-		img, err = d.getFixedOrientationImage(img, orientation)
+	for _, t := range d.transforms {
+		img, orientation, err = t(img, orientation)
+		if err != nil {
+			return nil, format, err
+		}
 	}
-	return img, format, err
+	return img, format, nil
 }
 
 // DecodeConfig decodes the color model and dimensions of an image
@@ -183,7 +220,8 @@ func (d *decoder) Decode(r io.Reader) (image.Image, string, error) {
 //
 // Note that after using imageorient.Decode on the same image,
 // the color model of the decoded image may be different if the
-// orientation-related transformation is needed.
+// orientation-related transformation is needed. The dimensions
+// may be different as well if other transforms are used.
 func (d *decoder) DecodeConfig(r io.Reader) (image.Config, string, error) {
 	orientation, r := getOrientation(r)
 
@@ -192,24 +230,160 @@ func (d *decoder) DecodeConfig(r io.Reader) (image.Config, string, error) {
 		return cfg, format, err
 	}
 
+	return orientedConfig(cfg, orientation), format, nil
+}
+
+// orientedConfig returns the given config with the dimensions swapped if
+// the EXIF orientation tag value means that the image is rotated by 90 degrees.
+func orientedConfig(cfg image.Config, orientation int) image.Config {
 	if orientation >= 5 && orientation <= 8 {
 		cfg.Width, cfg.Height = cfg.Height, cfg.Width
 	}
-
-	return cfg, format, nil
+	return cfg
 }
 
-// getFixedOrientationImage changes the image orientation based on the EXIF orientation tag value.
-func (d *decoder) getFixedOrientationImage(img image.Image, orientation int) (image.Image, error) {
-	filter, ok := d.FixOrientationFunctions[orientation]
-	if !ok {
-		return nil, errors.New(fmt.Sprintf("orientation %d not found in fixOrientationFunctions", orientation))
+// FixOrientation returns a Transform that changes the image orientation
+// based on the EXIF orientation tag value using the given functions.
+// The orientation passed to the following stages is 1 once it is fixed.
+func FixOrientation(fixOrientationFunctions map[int]FixOrientationFunction) Transform {
+	return func(img image.Image, orientation int) (image.Image, int, error) {
+		if orientation <= 1 {
+			return img, orientation, nil
+		}
+		filter, ok := fixOrientationFunctions[orientation]
+		if !ok {
+			return nil, orientation, errors.New(fmt.Sprintf("orientation %d not found in fixOrientationFunctions", orientation))
+		}
+		img, err := filter(img)
+		return img, 1, err
 	}
-	return filter(img)
 }
 
-func NewDecoder(fixOrientationFunctions map[int]FixOrientationFunction) Decoder {
+// NewDecoder returns a Decoder that fixes the image orientation using the given
+// functions. Additional transforms are applied after the orientation is fixed,
+// so they receive orientation 1 (or 0 if the image has no orientation tag).
+func NewDecoder(fixOrientationFunctions map[int]FixOrientationFunction, transforms ...Transform) Decoder {
+	return NewPipelineDecoder(append([]Transform{FixOrientation(fixOrientationFunctions)}, transforms...)...)
+}
+
+// NewPipelineDecoder returns a Decoder that applies the given transforms in order
+// to every decoded image. Use FixOrientation to add the orientation fix as one of
+// the stages, e.g. after a pre-processing hook and before post-processing ones.
+func NewPipelineDecoder(transforms ...Transform) Decoder {
 	return &decoder{
-		FixOrientationFunctions: fixOrientationFunctions,
+		transforms: transforms,
+	}
+}
+
+const (
+	// decoderOverhead is an upper bound of the memory used by the image
+	// decoders besides the decoded image (read buffers, Huffman tables, etc.).
+	decoderOverhead = 64 << 10
+
+	// fixBytesPerPixel is an upper bound of the number of bytes per pixel
+	// allocated by a FixOrientationFunction: an intermediate NRGBA copy of
+	// the source and the NRGBA destination image. It is doubled for images
+	// with 16 bits per channel.
+	fixBytesPerPixel = 8
+)
+
+// EstimateMemory returns an upper bound of the number of bytes that Decode
+// needs to decode an image with the given config, format and EXIF orientation
+// tag value, e.g. as returned by DecodeConfig and GetOrientation.
+//
+// The estimate includes the buffer used to read the EXIF metadata (which may
+// grow up to twice its limit), the decoder state, the decoded source image
+// and, if the orientation needs to be fixed, the memory used by a
+// FixOrientationFunction. For JPEG images it also accounts for the padding
+// to whole MCUs, the coefficients kept while decoding progressive images and
+// the intermediate planes of CMYK images. For PNG images it accounts for
+// the transparency information and the interlacing, which are not reflected
+// in the config.
+//
+// Memory allocated by other transforms of the decoder pipeline is not included.
+func EstimateMemory(cfg image.Config, format string, orientation int) int64 {
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	src := pixels * decodedBytesPerPixel(cfg.ColorModel, format)
+	switch format {
+	case "jpeg":
+		// Decoded planes are padded to whole MCUs of at most 16x16 pixels.
+		pixels = int64((cfg.Width+15)&^15) * int64((cfg.Height+15)&^15)
+		src = pixels * jpegBytesPerPixel(cfg.ColorModel)
+	case "png":
+		// Interlaced images are decoded pass by pass into separate images
+		// that are merged into the final one, so the image is needed twice.
+		// The current and previous rows take at most 8 bytes per pixel.
+		src = 2*src + 2*(int64(cfg.Width)*8+1)
+	}
+
+	size := 2*int64(MaxBufLen) + decoderOverhead + src
+	if orientation > 1 {
+		dst := int64(fixBytesPerPixel)
+		if decodedBytesPerPixel(cfg.ColorModel, format) > 4 {
+			dst *= 2
+		}
+		size += pixels * dst
+	}
+	return size
+}
+
+// decodedBytesPerPixel returns an upper bound of the number of bytes per pixel
+// of an image with the given color model decoded from the given format.
+func decodedBytesPerPixel(m color.Model, format string) int64 {
+	if format == "png" {
+		return pngBytesPerPixel(m)
+	}
+	return bytesPerPixel(m)
+}
+
+// pngBytesPerPixel returns an upper bound of the number of bytes per pixel
+// of a decoded PNG image with the given color model. Grayscale and truecolor
+// images with a tRNS chunk are decoded to NRGBA or NRGBA64 images, but the
+// chunk is not reflected in the color model.
+func pngBytesPerPixel(m color.Model) int64 {
+	if _, ok := m.(color.Palette); ok {
+		return 1
+	}
+	switch m {
+	case color.Gray16Model, color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	}
+	return 4
+}
+
+// jpegBytesPerPixel returns an upper bound of the number of bytes per pixel
+// allocated while decoding a JPEG image with the given color model.
+func jpegBytesPerPixel(m color.Model) int64 {
+	// Progressive images keep a block of 64 int32 coefficients for every
+	// 8x8 block of every component, i.e. 4 bytes per pixel per component.
+	const coeffBytesPerPixel = 4
+
+	switch m {
+	case color.GrayModel:
+		return 1 + 1*coeffBytesPerPixel
+	case color.CMYKModel:
+		// YCbCr and black planes combined into a CMYK image.
+		return 3 + 1 + 4 + 4*coeffBytesPerPixel
+	}
+	return 3 + 3*coeffBytesPerPixel
+}
+
+// bytesPerPixel returns the number of bytes per pixel of an image decoded
+// with the given color model. Unknown color models are assumed to take
+// 8 bytes per pixel.
+func bytesPerPixel(m color.Model) int64 {
+	if _, ok := m.(color.Palette); ok {
+		return 1
+	}
+	switch m {
+	case color.AlphaModel, color.GrayModel:
+		return 1
+	case color.Alpha16Model, color.Gray16Model:
+		return 2
+	case color.YCbCrModel:
+		return 3 // Assuming no chroma subsampling.
+	case color.RGBAModel, color.NRGBAModel, color.NYCbCrAModel, color.CMYKModel:
+		return 4
 	}
+	return 8
 }
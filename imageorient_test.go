@@ -2,7 +2,9 @@ package imageorient
 
 import (
 	"bytes"
+	"errors"
 	"image"
+	"image/color"
 	_ "image/jpeg"
 	"io/ioutil"
 	"os"
@@ -75,3 +77,118 @@ func TestDecodeShouldNotThrowErrorWhenNewDecoderIsPassingCompleteFixOperationFun
 		t.Errorf("Wanted nil error, got: %v", err)
 	}
 }
+
+func TestGetOrientation(t *testing.T) {
+	for _, tf := range testFiles {
+		b, err := ioutil.ReadFile(tf.path)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		o, r := GetOrientation(bytes.NewReader(b))
+		if o != tf.orientation {
+			t.Fatalf("expected orientation=%d but got %d (%s)", tf.orientation, o, tf.path)
+		}
+		rest, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !bytes.Equal(rest, b) {
+			t.Fatalf("returned reader does not match the original data (%s)", tf.path)
+		}
+	}
+}
+
+func TestEstimateMemory(t *testing.T) {
+	const base = 2*MaxBufLen + decoderOverhead
+
+	testCases := []struct {
+		cfg         image.Config
+		format      string
+		orientation int
+		want        int64
+	}{
+		{image.Config{ColorModel: color.GrayModel, Width: 10, Height: 20}, "png", 0, base + 2*800 + 2*81},
+		{image.Config{ColorModel: color.RGBA64Model, Width: 10, Height: 20}, "png", 3, base + 2*1600 + 2*81 + 3200},
+		{image.Config{ColorModel: color.Palette{color.Black}, Width: 10, Height: 20}, "png", 0, base + 2*200 + 2*81},
+		{image.Config{ColorModel: color.RGBA64Model, Width: 10, Height: 20}, "tiff", 3, base + 1600 + 3200},
+		{image.Config{ColorModel: color.Palette{color.Black}, Width: 10, Height: 20}, "gif", 2, base + 200 + 1600},
+		{image.Config{ColorModel: color.YCbCrModel, Width: 16, Height: 32}, "jpeg", 1, base + 512*15},
+		{image.Config{ColorModel: color.YCbCrModel, Width: 20, Height: 10}, "jpeg", 6, base + 512*15 + 512*8},
+		{image.Config{ColorModel: color.GrayModel, Width: 16, Height: 16}, "jpeg", 0, base + 256*5},
+		{image.Config{ColorModel: color.CMYKModel, Width: 16, Height: 16}, "jpeg", 0, base + 256*24},
+	}
+
+	for _, tc := range testCases {
+		got := EstimateMemory(tc.cfg, tc.format, tc.orientation)
+		if got != tc.want {
+			t.Errorf("EstimateMemory(%v, %q, %d): wanted %d, got %d", tc.cfg, tc.format, tc.orientation, tc.want, got)
+		}
+	}
+}
+
+func TestPipelineDecoderShouldApplyTransformsInOrder(t *testing.T) {
+	b, err := ioutil.ReadFile(testFiles[6].path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var calls []string
+	stage := func(name string, want int) Transform {
+		return func(img image.Image, orientation int) (image.Image, int, error) {
+			if orientation != want {
+				t.Errorf("%s: wanted orientation=%d, got %d", name, want, orientation)
+			}
+			calls = append(calls, name)
+			return img, orientation, nil
+		}
+	}
+	funcs := map[int]FixOrientationFunction{
+		6: func(img image.Image) (image.Image, error) {
+			calls = append(calls, "fix")
+			return img, nil
+		},
+	}
+	// Stages after the orientation fix should see the fixed orientation.
+	d := NewPipelineDecoder(stage("pre", testFiles[6].orientation), FixOrientation(funcs), stage("post", 1))
+
+	_, _, err = d.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Wanted nil error, got: %v", err)
+	}
+	want := []string{"pre", "fix", "post"}
+	if len(calls) != len(want) {
+		t.Fatalf("Wanted calls %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("Wanted calls %v, got %v", want, calls)
+		}
+	}
+}
+
+func TestPipelineDecoderShouldStopOnTransformError(t *testing.T) {
+	b, err := ioutil.ReadFile(testFiles[1].path)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	called := false
+	d := NewPipelineDecoder(
+		func(img image.Image, orientation int) (image.Image, int, error) {
+			return nil, orientation, errors.New("failed")
+		},
+		func(img image.Image, orientation int) (image.Image, int, error) {
+			called = true
+			return img, orientation, nil
+		},
+	)
+
+	_, _, err = d.Decode(bytes.NewReader(b))
+	if err == nil {
+		t.Errorf("Wanted not nil error, got nil error")
+	}
+	if called {
+		t.Errorf("Wanted transforms after the failed one not to be called")
+	}
+}
@@ -0,0 +1,146 @@
+// Package orient provides the default orientation fix functions and the
+// decode-fix-encode round trip shared by the language bindings.
+package orient
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/adalberht/imageorient"
+)
+
+// jpegQuality is the quality used to re-encode JPEG images.
+const jpegQuality = 95
+
+// FixOrientationFunctions returns the functions fixing every
+// EXIF orientation tag value from 2 to 8.
+func FixOrientationFunctions() map[int]imageorient.FixOrientationFunction {
+	funcs := make(map[int]imageorient.FixOrientationFunction)
+	for o := 2; o <= 8; o++ {
+		funcs[o] = fixFunc(o)
+	}
+	return funcs
+}
+
+// fixFunc returns the function fixing the given orientation. The function
+// moves every source pixel (x, y) of a w×h image to its destination position
+// in a new NRGBA image.
+func fixFunc(orientation int) imageorient.FixOrientationFunction {
+	var (
+		swap bool
+		move func(x, y, w, h int) (int, int)
+	)
+	switch orientation {
+	case 2: // Flip horizontally.
+		move = func(x, y, w, h int) (int, int) { return w - 1 - x, y }
+	case 3: // Rotate 180 degrees.
+		move = func(x, y, w, h int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // Flip vertically.
+		move = func(x, y, w, h int) (int, int) { return x, h - 1 - y }
+	case 5: // Transpose.
+		swap, move = true, func(x, y, w, h int) (int, int) { return y, x }
+	case 6: // Rotate 90 degrees clockwise.
+		swap, move = true, func(x, y, w, h int) (int, int) { return h - 1 - y, x }
+	case 7: // Transverse.
+		swap, move = true, func(x, y, w, h int) (int, int) { return h - 1 - y, w - 1 - x }
+	case 8: // Rotate 90 degrees counter-clockwise.
+		swap, move = true, func(x, y, w, h int) (int, int) { return y, w - 1 - x }
+	}
+
+	return func(img image.Image) (image.Image, error) {
+		b := img.Bounds()
+		w, h := b.Dx(), b.Dy()
+		read := pixelReader(img)
+
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		if swap {
+			dst = image.NewNRGBA(image.Rect(0, 0, h, w))
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dx, dy := move(x, y, w, h)
+				i := dst.PixOffset(dx, dy)
+				read(b.Min.X+x, b.Min.Y+y, dst.Pix[i:i+4])
+			}
+		}
+		return dst, nil
+	}
+}
+
+// pixelReader returns a function that stores the NRGBA value of the pixel
+// (x, y) of the given image into p. The common image types produced by the
+// standard decoders are read directly, other images are converted to NRGBA
+// first.
+func pixelReader(img image.Image) func(x, y int, p []uint8) {
+	switch img := img.(type) {
+	case *image.NRGBA:
+		return func(x, y int, p []uint8) {
+			i := img.PixOffset(x, y)
+			copy(p, img.Pix[i:i+4])
+		}
+	case *image.YCbCr:
+		return func(x, y int, p []uint8) {
+			yi, ci := img.YOffset(x, y), img.COffset(x, y)
+			p[0], p[1], p[2] = color.YCbCrToRGB(img.Y[yi], img.Cb[ci], img.Cr[ci])
+			p[3] = 0xff
+		}
+	case *image.Gray:
+		return func(x, y int, p []uint8) {
+			v := img.Pix[img.PixOffset(x, y)]
+			p[0], p[1], p[2], p[3] = v, v, v, 0xff
+		}
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(b)
+	draw.Draw(src, b, img, b.Min, draw.Src)
+	return pixelReader(src)
+}
+
+// DecodeAndFix decodes the given image, fixes its orientation according to
+// the EXIF orientation tag (if present) and encodes it back. JPEG images are
+// encoded as JPEG, all other formats as PNG. The returned format is the
+// format of the returned image.
+//
+// Images that do not need their orientation fixed are returned unchanged
+// with fixed set to false, so that they do not lose quality by re-encoding.
+func DecodeAndFix(data []byte) (out []byte, format string, fixed bool, err error) {
+	orientation, r := imageorient.GetOrientation(bytes.NewReader(data))
+	if orientation <= 1 {
+		// Only check that the data is a supported image.
+		_, format, err := image.DecodeConfig(r)
+		if err != nil {
+			return nil, format, false, err
+		}
+		return data, format, false, nil
+	}
+
+	d := imageorient.NewDecoder(FixOrientationFunctions())
+	img, format, err := d.Decode(r)
+	if err != nil {
+		return nil, format, false, err
+	}
+
+	buf := new(bytes.Buffer)
+	if format == "jpeg" {
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		format = "png"
+		err = png.Encode(buf, img)
+	}
+	if err != nil {
+		return nil, format, false, err
+	}
+	return buf.Bytes(), format, true, nil
+}
+
+// GetOrientation returns the EXIF orientation tag from the given image
+// (0 if not found or invalid).
+func GetOrientation(data []byte) int {
+	orientation, _ := imageorient.GetOrientation(bytes.NewReader(data))
+	return orientation
+}
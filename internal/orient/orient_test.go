@@ -0,0 +1,125 @@
+package orient
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"testing"
+
+	"github.com/adalberht/imageorient"
+)
+
+func TestFixOrientationFunctions(t *testing.T) {
+	// Source image:
+	//   0 1 2
+	//   3 4 5
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+
+	testCases := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{2, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{3, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{4, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{5, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{6, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{7, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{8, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+
+	// The same pixels as other image types, including one with a non-zero origin.
+	srcs := []image.Image{src}
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 3, 2), image.YCbCrSubsampleRatio444)
+	nrgba := image.NewNRGBA(image.Rect(5, 5, 8, 7))
+	gray16 := image.NewGray16(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		ycbcr.Y[i], ycbcr.Cb[i], ycbcr.Cr[i] = uint8(i), 0x80, 0x80
+		nrgba.Set(5+i%3, 5+i/3, color.Gray{uint8(i)})
+		gray16.Set(i%3, i/3, color.Gray{uint8(i)})
+	}
+	srcs = append(srcs, ycbcr, nrgba, gray16)
+
+	funcs := FixOrientationFunctions()
+	for _, src := range srcs {
+		for _, tc := range testCases {
+			testFixOrientationFunction(t, funcs[tc.orientation], src, tc.orientation, tc.want)
+		}
+	}
+}
+
+func testFixOrientationFunction(t *testing.T, fix imageorient.FixOrientationFunction, src image.Image, orientation int, want [][]uint8) {
+	img, err := fix(src)
+	if err != nil {
+		t.Fatalf("orientation %d (%T): %v", orientation, src, err)
+	}
+	b := img.Bounds()
+	if b.Dx() != len(want[0]) || b.Dy() != len(want) {
+		t.Fatalf("orientation %d (%T): wanted %dx%d, got %dx%d", orientation, src, len(want[0]), len(want), b.Dx(), b.Dy())
+	}
+	for y, row := range want {
+		for x, v := range row {
+			got := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+			if got != v {
+				t.Errorf("orientation %d (%T): wanted %d at (%d, %d), got %d", orientation, src, v, x, y, got)
+			}
+		}
+	}
+}
+
+func TestDecodeAndFix(t *testing.T) {
+	data, err := ioutil.ReadFile("../../testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if o := GetOrientation(data); o != 6 {
+		t.Fatalf("wanted orientation=6, got %d", o)
+	}
+
+	out, format, fixed, err := DecodeAndFix(data)
+	if err != nil {
+		t.Fatalf("DecodeAndFix: %v", err)
+	}
+	if format != "jpeg" || !fixed {
+		t.Errorf("wanted fixed jpeg, got format=%s fixed=%v", format, fixed)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	outCfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if outCfg.Width != cfg.Height || outCfg.Height != cfg.Width {
+		t.Errorf("wanted %dx%d, got %dx%d", cfg.Height, cfg.Width, outCfg.Width, outCfg.Height)
+	}
+}
+
+func TestDecodeAndFixShouldReturnUnchangedImages(t *testing.T) {
+	for _, path := range []string{"../../testdata/orientation_0.jpg", "../../testdata/orientation_1.jpg"} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		out, format, fixed, err := DecodeAndFix(data)
+		if err != nil {
+			t.Fatalf("DecodeAndFix(%q): %v", path, err)
+		}
+		if format != "jpeg" || fixed {
+			t.Errorf("%s: wanted unfixed jpeg, got format=%s fixed=%v", path, format, fixed)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("%s: wanted the data to be returned unchanged", path)
+		}
+	}
+
+	if _, _, _, err := DecodeAndFix([]byte("not an image")); err == nil {
+		t.Errorf("wanted not nil error, got nil error")
+	}
+}
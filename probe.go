@@ -0,0 +1,89 @@
+package imageorient
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ProbeResult holds the metadata of an image as returned by Probe.
+//
+// Only the presence of EXIF metadata is reported. Other metadata,
+// such as XMP or ICC profiles, is not detected.
+type ProbeResult struct {
+	// Format is the format name used during format registration, e.g. "jpeg".
+	Format string `json:"format"`
+	// Width and Height are the image dimensions with the respect to
+	// the EXIF orientation tag (if present).
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Orientation is the EXIF orientation tag value (0 if not found or invalid).
+	Orientation int `json:"orientation"`
+	// ColorModel is the name of the color model reported by the image config.
+	// Note that PNG images with transparency information may be decoded with
+	// a different color model, e.g. NRGBA for grayscale images.
+	ColorModel string `json:"color_model"`
+	// HasEXIF reports whether the image contains EXIF metadata.
+	HasEXIF bool `json:"has_exif"`
+	// HasOrientation reports whether the EXIF metadata contains a valid orientation tag.
+	HasOrientation bool `json:"has_orientation"`
+	// EstimatedSize is an upper bound of the size in bytes of the decoded source
+	// image, i.e. before any transforms. See EstimateMemory for the peak memory
+	// needed to decode the image and fix its orientation.
+	EstimatedSize int64 `json:"estimated_size"`
+}
+
+// Probe reads the metadata of an image without decoding its pixels.
+func Probe(r io.Reader) (ProbeResult, error) {
+	orientation, hasEXIF, r := getEXIF(r)
+
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	cfg = orientedConfig(cfg, orientation)
+
+	return ProbeResult{
+		Format:         format,
+		Width:          cfg.Width,
+		Height:         cfg.Height,
+		Orientation:    orientation,
+		ColorModel:     colorModelName(cfg.ColorModel),
+		HasEXIF:        hasEXIF,
+		HasOrientation: orientation != 0,
+		EstimatedSize:  int64(cfg.Width) * int64(cfg.Height) * decodedBytesPerPixel(cfg.ColorModel, format),
+	}, nil
+}
+
+// colorModelName returns a human-readable name of the given color model.
+func colorModelName(m color.Model) string {
+	if _, ok := m.(color.Palette); ok {
+		return "Paletted"
+	}
+	switch m {
+	case color.AlphaModel:
+		return "Alpha"
+	case color.Alpha16Model:
+		return "Alpha16"
+	case color.GrayModel:
+		return "Gray"
+	case color.Gray16Model:
+		return "Gray16"
+	case color.YCbCrModel:
+		return "YCbCr"
+	case color.NYCbCrAModel:
+		return "NYCbCrA"
+	case color.CMYKModel:
+		return "CMYK"
+	case color.RGBAModel:
+		return "RGBA"
+	case color.RGBA64Model:
+		return "RGBA64"
+	case color.NRGBAModel:
+		return "NRGBA"
+	case color.NRGBA64Model:
+		return "NRGBA64"
+	}
+	return fmt.Sprintf("%T", m)
+}
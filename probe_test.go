@@ -0,0 +1,73 @@
+package imageorient
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	for _, tf := range testFiles {
+		f, err := os.Open(tf.path)
+		if err != nil {
+			t.Fatalf("os.Open(%q): %v", tf.path, err)
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("image.DecodeConfig(%q): %v", tf.path, err)
+		}
+
+		f, err = os.Open(tf.path)
+		if err != nil {
+			t.Fatalf("os.Open(%q): %v", tf.path, err)
+		}
+		p, err := Probe(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Probe(%q): %v", tf.path, err)
+		}
+
+		if tf.orientation >= 5 {
+			cfg.Width, cfg.Height = cfg.Height, cfg.Width
+		}
+		want := ProbeResult{
+			Format:         "jpeg",
+			Width:          cfg.Width,
+			Height:         cfg.Height,
+			Orientation:    tf.orientation,
+			ColorModel:     "YCbCr",
+			HasEXIF:        tf.orientation != 0,
+			HasOrientation: tf.orientation != 0,
+			EstimatedSize:  int64(cfg.Width) * int64(cfg.Height) * 3,
+		}
+		if p != want {
+			t.Errorf("Probe(%q): wanted %+v, got %+v", tf.path, want, p)
+		}
+	}
+}
+
+func TestProbeJSON(t *testing.T) {
+	// Grayscale PNG images may be decoded to NRGBA, so the estimated size is 4 bytes per pixel.
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	p, err := Probe(&buf)
+	if err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	want := `{"format":"png","width":3,"height":2,"orientation":0,"color_model":"Gray","has_exif":false,"has_orientation":false,"estimated_size":24}`
+	if string(b) != want {
+		t.Errorf("wanted %s, got %s", want, b)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command wasm exposes the imageorient orientation handling to JavaScript.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o imageorient.wasm github.com/adalberht/imageorient/wasm
+//
+// Once the module is running (see wasm_exec.js from the Go distribution),
+// a global imageorient object provides the following functions:
+//
+//	imageorient.getOrientation(data: Uint8Array): number
+//	imageorient.decodeAndFix(data: Uint8Array): {data: Uint8Array, format: string, fixed: boolean} | Error
+//
+// decodeAndFix re-encodes JPEG images as JPEG and all other formats as PNG.
+// Images that do not need their orientation fixed are returned unchanged
+// with fixed set to false. It returns an Error object if the image cannot
+// be processed.
+//
+// The bindings require Go 1.13 or later.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/adalberht/imageorient/internal/orient"
+)
+
+func main() {
+	js.Global().Set("imageorient", js.ValueOf(map[string]interface{}{
+		"decodeAndFix":   js.FuncOf(decodeAndFix),
+		"getOrientation": js.FuncOf(getOrientation),
+	}))
+	select {}
+}
+
+func decodeAndFix(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return js.Global().Get("Error").New("decodeAndFix: expected 1 argument")
+	}
+	if !isUint8Array(args[0]) {
+		return js.Global().Get("Error").New("decodeAndFix: expected a Uint8Array argument")
+	}
+	data, format, fixed, err := orient.DecodeAndFix(bytesFromJS(args[0]))
+	if err != nil {
+		return js.Global().Get("Error").New("decodeAndFix: " + err.Error())
+	}
+	if !fixed {
+		return map[string]interface{}{
+			"data":   args[0],
+			"format": format,
+			"fixed":  false,
+		}
+	}
+	return map[string]interface{}{
+		"data":   bytesToJS(data),
+		"format": format,
+		"fixed":  true,
+	}
+}
+
+func getOrientation(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return js.Global().Get("Error").New("getOrientation: expected 1 argument")
+	}
+	if !isUint8Array(args[0]) {
+		return js.Global().Get("Error").New("getOrientation: expected a Uint8Array argument")
+	}
+	return orient.GetOrientation(bytesFromJS(args[0]))
+}
+
+// isUint8Array reports whether v is a Uint8Array. Other values would make
+// js.CopyBytesToGo panic, which exits the Go program.
+func isUint8Array(v js.Value) bool {
+	return v.InstanceOf(js.Global().Get("Uint8Array"))
+}
+
+// bytesFromJS copies the contents of the given Uint8Array.
+func bytesFromJS(v js.Value) []byte {
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+// bytesToJS returns a new Uint8Array with the contents of b.
+func bytesToJS(b []byte) js.Value {
+	v := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(v, b)
+	return v
+}
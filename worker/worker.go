@@ -0,0 +1,107 @@
+// Package worker processes image orientation fix jobs pulled from a queue.
+//
+// Queue systems (NATS, SQS, Kafka, ...) are plugged in by implementing
+// the Consumer and Job interfaces on top of their clients.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/adalberht/imageorient"
+)
+
+// Job is a single image to be processed.
+type Job interface {
+	// Open returns the encoded image data.
+	Open() (io.ReadCloser, error)
+	// Done reports the decoded image, e.g. by storing it and acknowledging the message.
+	Done(img image.Image, format string) error
+	// Fail reports that the image could not be processed.
+	Fail(err error) error
+}
+
+// Consumer pulls jobs from a queue.
+type Consumer interface {
+	// Next blocks until the next job is available or the context is done.
+	// It returns io.EOF when there are no more jobs. It may return a nil job
+	// and a nil error, e.g. when a poll times out, in which case the worker
+	// calls Next again.
+	Next(ctx context.Context) (Job, error)
+}
+
+// Worker decodes the images of the jobs pulled from a Consumer.
+type Worker struct {
+	decoder  imageorient.Decoder
+	consumer Consumer
+}
+
+// New returns a Worker that decodes the images of the jobs
+// pulled from the given consumer using the given decoder.
+func New(decoder imageorient.Decoder, consumer Consumer) *Worker {
+	return &Worker{
+		decoder:  decoder,
+		consumer: consumer,
+	}
+}
+
+// Run processes jobs until the consumer returns io.EOF, in which case it
+// returns nil, or until the context is done, in which case it returns ctx.Err().
+//
+// Errors decoding an image are reported with Job.Fail. Errors returned by the
+// consumer or by Job.Done and Job.Fail stop the worker, as they usually mean that
+// the queue connection is broken. Adapters that can recover from transient
+// failures, e.g. by retrying the acknowledgement, should do so in Done and Fail
+// and only return the errors the worker should not continue after.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		job, err := w.consumer.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if job == nil {
+			continue
+		}
+		if err := w.process(job); err != nil {
+			return err
+		}
+	}
+}
+
+// process decodes the image of the given job and reports the result.
+// Panics while decoding the image, e.g. in a transform of the decoder,
+// are reported with Job.Fail as well.
+func (w *Worker) process(job Job) error {
+	img, format, err := w.decode(job)
+	if err != nil {
+		return job.Fail(err)
+	}
+	return job.Done(img, format)
+}
+
+// decode decodes the image of the given job, recovering from panics.
+func (w *Worker) decode(job Job) (img image.Image, format string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			img, format, err = nil, "", fmt.Errorf("worker: panic while decoding: %v", r)
+		}
+	}()
+
+	rc, err := job.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	return w.decoder.Decode(rc)
+}
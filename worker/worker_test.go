@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/adalberht/imageorient"
+)
+
+type testJob struct {
+	data    []byte
+	format  string
+	err     error
+	done    bool
+	doneErr error
+}
+
+func (j *testJob) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(j.data)), nil
+}
+
+func (j *testJob) Done(img image.Image, format string) error {
+	j.done = true
+	j.format = format
+	return j.doneErr
+}
+
+func (j *testJob) Fail(err error) error {
+	j.err = err
+	return nil
+}
+
+type testConsumer struct {
+	jobs []Job
+	err  error
+}
+
+func (c *testConsumer) Next(ctx context.Context) (Job, error) {
+	if len(c.jobs) == 0 {
+		return nil, c.err
+	}
+	job := c.jobs[0]
+	c.jobs = c.jobs[1:]
+	return job, nil
+}
+
+// blockingConsumer blocks until the context is done.
+type blockingConsumer struct{}
+
+func (blockingConsumer) Next(ctx context.Context) (Job, error) {
+	<-ctx.Done()
+	return nil, errors.New("consumer closed")
+}
+
+func testImage(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRun(t *testing.T) {
+	ok := &testJob{data: testImage(t)}
+	bad := &testJob{data: []byte("not an image")}
+
+	w := New(imageorient.NewDecoder(nil), &testConsumer{jobs: []Job{ok, bad}, err: io.EOF})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Wanted nil error, got: %v", err)
+	}
+
+	if !ok.done || ok.format != "png" || ok.err != nil {
+		t.Errorf("Wanted job to be done with format png, got done=%v format=%q err=%v", ok.done, ok.format, ok.err)
+	}
+	if bad.done || bad.err == nil {
+		t.Errorf("Wanted job to fail, got done=%v err=%v", bad.done, bad.err)
+	}
+}
+
+func TestRunShouldReturnConsumerError(t *testing.T) {
+	want := errors.New("connection lost")
+	w := New(imageorient.NewDecoder(nil), &testConsumer{err: want})
+	if err := w.Run(context.Background()); err != want {
+		t.Errorf("Wanted error %v, got: %v", want, err)
+	}
+}
+
+func TestRunShouldReturnDoneError(t *testing.T) {
+	want := errors.New("ack failed")
+	failed := &testJob{data: testImage(t), doneErr: want}
+	next := &testJob{data: testImage(t)}
+
+	w := New(imageorient.NewDecoder(nil), &testConsumer{jobs: []Job{failed, next}, err: io.EOF})
+	if err := w.Run(context.Background()); err != want {
+		t.Errorf("Wanted error %v, got: %v", want, err)
+	}
+	if next.done {
+		t.Errorf("Wanted no jobs to be processed after the error")
+	}
+}
+
+func TestRunShouldStopOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- New(imageorient.NewDecoder(nil), blockingConsumer{}).Run(ctx)
+	}()
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("Wanted error %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestRunShouldSkipNilJobs(t *testing.T) {
+	ok := &testJob{data: testImage(t)}
+
+	w := New(imageorient.NewDecoder(nil), &testConsumer{jobs: []Job{nil, ok, nil}, err: io.EOF})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Wanted nil error, got: %v", err)
+	}
+	if !ok.done {
+		t.Errorf("Wanted job to be done")
+	}
+}
+
+func TestRunShouldFailJobsOnPanic(t *testing.T) {
+	panicking := &testJob{data: testImage(t)}
+	next := &testJob{data: testImage(t)}
+
+	calls := 0
+	d := imageorient.NewDecoder(nil, func(img image.Image, orientation int) (image.Image, int, error) {
+		calls++
+		if calls == 1 {
+			panic("transform failed")
+		}
+		return img, orientation, nil
+	})
+	w := New(d, &testConsumer{jobs: []Job{panicking, next}, err: io.EOF})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Wanted nil error, got: %v", err)
+	}
+
+	if panicking.done || panicking.err == nil {
+		t.Errorf("Wanted job to fail, got done=%v err=%v", panicking.done, panicking.err)
+	}
+	if !next.done {
+		t.Errorf("Wanted the next job to be done")
+	}
+}